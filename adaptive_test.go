@@ -0,0 +1,122 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveCapacityGrows(t *testing.T) {
+	q := New(WithAdaptiveCapacity(1, 3, WithAdaptiveInterval(10*time.Millisecond)))
+	defer q.Close()
+
+	require.Equal(t, 1, q.Capacity())
+	require.Eventually(t, func() bool {
+		return q.Capacity() == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAdaptiveCapacityBacksOff(t *testing.T) {
+	var backoff int32
+	q := New(WithAdaptiveCapacity(1, 10,
+		WithAdaptiveInterval(10*time.Millisecond),
+		WithBackoffFactor(0.5),
+		WithBackoffSignal(func() bool { return atomic.LoadInt32(&backoff) != 0 }),
+	))
+	defer q.Close()
+
+	require.Eventually(t, func() bool {
+		return q.Capacity() >= 4
+	}, time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt32(&backoff, 1)
+	require.Eventually(t, func() bool {
+		return q.Capacity() == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAdaptiveCapacityUnblocksPushersOnGrowth(t *testing.T) {
+	q := New(WithAdaptiveCapacity(1, 2, WithAdaptiveInterval(10*time.Millisecond)))
+	defer q.Close()
+
+	require.NoError(t, q.Push(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := q.Push(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, q.Len())
+}
+
+func TestAdaptiveCapacityUnblocksPushersOnGrowthAcrossPriorities(t *testing.T) {
+	q := New(WithPriorities(2, StrictPriority()), WithAdaptiveCapacity(1, 10, WithAdaptiveInterval(10*time.Millisecond)))
+	defer q.Close()
+
+	require.NoError(t, q.PushWithPriority(context.Background(), 1, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// blocked on priority class 1, which growth must also unblock, not
+	// just class 0.
+	err := q.PushWithPriority(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, q.Len())
+}
+
+func TestAdaptiveCapacityBackoffSignalCanCallBackIntoQueue(t *testing.T) {
+	var q *Bloque
+	q = New(WithAdaptiveCapacity(1, 10,
+		WithAdaptiveInterval(10*time.Millisecond),
+		// regression test: backoffSignal() must not be called while
+		// q.mutex is held, otherwise a signal that calls back into the
+		// queue (a very natural thing to do) deadlocks the calculator
+		// goroutine forever.
+		WithBackoffSignal(func() bool {
+			q.Len()
+			return false
+		}),
+	))
+	defer q.Close()
+
+	require.Eventually(t, func() bool {
+		return q.Capacity() >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, q.Push(ctx, 1))
+}
+
+func TestAdaptiveCapacityChangeCallback(t *testing.T) {
+	changes := make(chan [2]int, 8)
+	q := New(WithAdaptiveCapacity(1, 2, WithAdaptiveInterval(10*time.Millisecond),
+		WithCapacityChangeCallback(func(old, new int) {
+			changes <- [2]int{old, new}
+		})))
+	defer q.Close()
+
+	select {
+	case change := <-changes:
+		require.Equal(t, [2]int{1, 2}, change)
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for capacity change callback")
+	}
+}
+
+func TestAdaptiveCapacityCloseIsIdempotent(t *testing.T) {
+	q := New(WithAdaptiveCapacity(1, 2, WithAdaptiveInterval(10*time.Millisecond)))
+
+	// Close() used to unconditionally close(q.adaptive.stopChan), so a
+	// second call panicked with "close of closed channel" -- a regression
+	// from before WithAdaptiveCapacity(), when Close() was safe to call
+	// repeatedly.
+	require.NotPanics(t, func() {
+		q.Close()
+		q.Close()
+	})
+}
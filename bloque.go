@@ -7,26 +7,97 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
 var (
 	// ErrMaxWaiters is returned when maximum number of blocked goroutines
 	// on Push() or Pop() calls is reached.
 	ErrMaxWaiters = fmt.Errorf("max waiters reached")
+
+	// ErrQueueClosed is returned when a Push or Pop operation is attempted
+	// after or has been unblocked due to queue being closed.
+	ErrQueueClosed = fmt.Errorf("queue is closed")
+
+	// ErrQueueFull is returned by TryPush() when the queue is at capacity.
+	ErrQueueFull = fmt.Errorf("queue is full")
+
+	// ErrEmptyQueue is returned by TryPop() and Peek() when the queue has
+	// no items.
+	ErrEmptyQueue = fmt.Errorf("queue is empty")
+
+	// ErrInvalidPriority is returned by PushWithPriority() when prio is
+	// outside the range configured by WithPriorities().
+	ErrInvalidPriority = fmt.Errorf("invalid priority")
 )
 
 // Bloque is a simple implementation of a blocking fifo queue. It allows
 // various constrains to be specified such as maximum capacity, maximum waiters
 // and so on.
 type Bloque struct {
-	itemQueue      *list.List
+	// itemQueues holds one fifo list per priority class. Unless
+	// WithPriorities() is used, it holds a single list and Push()/Pop()
+	// always address itemQueues[0].
+	itemQueues     []*list.List
 	capacity       int
 	maxPushWaiters int
 	maxPopWaiters  int
-	mutex          sync.Mutex
+	closed         bool
+	// draining is set by Drain() to reject new Push calls while still
+	// letting existing items and already-blocked push waiters flow to
+	// Pop consumers. See Drain() and WithGracefulShutdown().
+	draining bool
+	// gracefulShutdown makes Close() first drain as Drain(context.Background())
+	// would, then hard-stop any remaining waiters. Set by WithGracefulShutdown().
+	gracefulShutdown bool
+	// pendingPushes counts Push()/PushWithPriority()/PushN() calls that are
+	// past the draining check and still working towards actually landing
+	// their item, whether or not they are currently blocked on capacity.
+	// drainDoneLocked() must wait for this to reach zero: a call can be
+	// dequeued from pushWaitersLists to be woken up well before it
+	// re-acquires q.mutex and pushes its item, so pushWaitersLists being
+	// empty is not by itself proof that draining has finished.
+	pendingPushes int
+	mutex         sync.Mutex
+
+	// pushWaitersLists holds one push waiters list per priority class, so
+	// that capacity backpressure on Push() is applied per class.
+	pushWaitersLists []*list.List
+	// popWaitersList is shared across all priority classes: a Pop() call
+	// is satisfied by an item becoming available in any of them.
+	popWaitersList *list.List
+	// drainWaitersList holds goroutines blocked in Drain(), woken once the
+	// queue is empty and no Push() call is still in flight. See
+	// drainDoneLocked().
+	drainWaitersList *list.List
+
+	// priorityPolicy decides which priority class Pop() drains from next.
+	// It is nil unless WithPriorities() was used.
+	priorityPolicy PriorityPolicy
+
+	// adaptive holds the AIMD adaptive capacity state. It is nil unless
+	// WithAdaptiveCapacity() was used.
+	adaptive *adaptiveState
 
-	pushWaitersList *list.List
-	popWaitersList  *list.List
+	// pushLimiter and popLimiter throttle Push() and Pop() respectively.
+	// They are nil unless WithPushRateLimit()/WithPopRateLimit() was used.
+	pushLimiter       *rate.Limiter
+	popLimiter        *rate.Limiter
+	rateLimitFailFast bool
+
+	// observer receives queue event callbacks. It is nil unless
+	// WithObserver() was used.
+	observer Observer
+	// Stats() counters and moving averages, all protected by mutex.
+	totalPushed      uint64
+	totalPopped      uint64
+	totalPushDropped uint64
+	totalPopDropped  uint64
+	totalTimedOut    uint64
+	avgPushWaitNs    float64
+	avgPopWaitNs     float64
 }
 
 // waiter is used to represent a waiting call.
@@ -47,9 +118,10 @@ type waiter struct {
 // New creates a new Bloque with opts.
 func New(opts ...optionFunc) *Bloque {
 	b := &Bloque{
-		itemQueue:       list.New(),
-		pushWaitersList: list.New(),
-		popWaitersList:  list.New(),
+		itemQueues:       []*list.List{list.New()},
+		pushWaitersLists: []*list.List{list.New()},
+		popWaitersList:   list.New(),
+		drainWaitersList: list.New(),
 	}
 
 	for _, opt := range opts {
@@ -65,15 +137,53 @@ func New(opts ...optionFunc) *Bloque {
 // Returns ErrMaxWaiters if maximum number of waiting goroutines is reached
 // as specified by WithMaxPushWaiters() option.
 func (q *Bloque) Push(ctx context.Context, item interface{}) error {
+	return q.push(ctx, item, 0)
+}
+
+// PushWithPriority puts item at the back of the priority-th priority class,
+// as configured by WithPriorities(). Classes are numbered 0 (highest) to
+// n-1 (lowest). It behaves the same as Push() otherwise, including
+// blocking and backpressure semantics, which are applied per priority
+// class. Returns ErrInvalidPriority if prio is out of range.
+func (q *Bloque) PushWithPriority(ctx context.Context, item interface{}, prio int) error {
+	if prio < 0 || prio >= len(q.itemQueues) {
+		return ErrInvalidPriority
+	}
+	return q.push(ctx, item, prio)
+}
+
+func (q *Bloque) push(ctx context.Context, item interface{}, prio int) error {
+	if err := q.reserveRateLimit(ctx, q.pushLimiter); err != nil {
+		return err
+	}
+
 	q.mutex.Lock()
 
+	if q.draining {
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+
+	itemQueue := q.itemQueues[prio]
+	pushWaitersList := q.pushWaitersLists[prio]
+	var waited time.Duration
+	blocked := false
+
 	// similar to condition variables spurious wake ups where our
 	// blocked goroutine would be notified but someone else beat us
 	// to the item.
-	for q.capacity > 0 && q.itemQueue.Len() >= q.capacity {
+	for !q.closed && q.capacity > 0 && itemQueue.Len() >= q.capacity {
+		if !blocked {
+			q.pendingPushes++
+			blocked = true
+		}
+
 		// straight up, do not exceed waiters constrain
-		if q.maxPushWaiters > 0 && q.pushWaitersList.Len() >= q.maxPushWaiters {
+		if q.maxPushWaiters > 0 && pushWaitersList.Len() >= q.maxPushWaiters {
+			q.recordDroppedLocked(WaitKindPush)
+			q.pendingPushes--
 			q.mutex.Unlock()
+			q.notifyMaxWaiters()
 			return ErrMaxWaiters
 		}
 
@@ -81,45 +191,242 @@ func (q *Bloque) Push(ctx context.Context, item interface{}) error {
 			waitChan: make(chan interface{}),
 			waiting:  true,
 		}
-		q.pushWaitersList.PushBack(waiterItem)
+		waiterListElement := pushWaitersList.PushBack(waiterItem)
 		q.mutex.Unlock()
 
+		waitStart := time.Now()
 		select {
 		case <-waiterItem.waitChan:
+			waited += time.Since(waitStart)
 			q.mutex.Lock()
 			continue
 		case <-ctx.Done():
+			waited += time.Since(waitStart)
 			waiterItem.mutex.Lock()
 			waiterItem.waiting = false
 			if waiterItem.fired {
 				// race detected, must pass on to the next waiter
 				waiterItem.mutex.Unlock()
 				q.mutex.Lock()
-				q.unblockNextWaiterLocked(q.pushWaitersList)
+				q.unblockNextWaiterLocked(pushWaitersList)
+				q.recordWaitLocked(waited, WaitKindPush, true)
+				q.pendingPushes--
+				q.checkDrainDoneLocked()
 				q.mutex.Unlock()
 			} else {
 				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				pushWaitersList.Remove(waiterListElement)
+				q.recordWaitLocked(waited, WaitKindPush, true)
+				q.pendingPushes--
+				q.checkDrainDoneLocked()
+				q.mutex.Unlock()
 			}
+			q.notifyWait(waited, WaitKindPush, true)
 			return ctx.Err()
 		}
 	}
 
-	q.itemQueue.PushBack(item)
+	if blocked {
+		q.pendingPushes--
+	}
+
+	if q.closed {
+		q.checkDrainDoneLocked()
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+
+	itemQueue.PushBack(item)
 	q.unblockNextWaiterLocked(q.popWaitersList)
+	q.recordPushLocked(1)
+	if waited > 0 {
+		q.recordWaitLocked(waited, WaitKindPush, false)
+	}
 	q.mutex.Unlock()
+
+	if waited > 0 {
+		q.notifyWait(waited, WaitKindPush, false)
+	}
+	q.notifyPush()
 	return nil
 }
 
+// TryPush puts item at the back of the queue without blocking. It returns
+// ErrQueueFull if queue capacity (as specified by WithCapacity() option) is
+// reached, or ErrQueueClosed if the queue has been closed. When
+// WithPriorities() is used, item is pushed to the highest priority class.
+// Returns ErrRateLimited if WithPushRateLimit() is set and no token is
+// currently available, since TryPush() cannot block to wait for one.
+func (q *Bloque) TryPush(item interface{}) error {
+	if err := q.tryReserveRateLimit(q.pushLimiter); err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+
+	itemQueue := q.itemQueues[0]
+	if q.closed || q.draining {
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+	if q.capacity > 0 && itemQueue.Len() >= q.capacity {
+		q.recordDroppedLocked(WaitKindPush)
+		q.mutex.Unlock()
+		return ErrQueueFull
+	}
+
+	itemQueue.PushBack(item)
+	q.unblockNextWaiterLocked(q.popWaitersList)
+	q.recordPushLocked(1)
+	q.mutex.Unlock()
+
+	q.notifyPush()
+	return nil
+}
+
+// PushN pushes up to len(items) items at the back of the queue, blocking
+// as needed until either all items are pushed, ctx is cancelled, or the
+// queue is closed. It returns the number of items actually pushed.
+// Unlike repeated calls to Push(), waiters are woken in a single broadcast
+// pass per batch rather than one at a time. As with Push(), a single
+// WithPushRateLimit() token is reserved for the whole call, not per item.
+func (q *Bloque) PushN(ctx context.Context, items []interface{}) (int, error) {
+	if err := q.reserveRateLimit(ctx, q.pushLimiter); err != nil {
+		return 0, err
+	}
+
+	pushed := 0
+	for pushed < len(items) {
+		q.mutex.Lock()
+
+		if q.draining {
+			q.mutex.Unlock()
+			return pushed, ErrQueueClosed
+		}
+
+		itemQueue := q.itemQueues[0]
+		pushWaitersList := q.pushWaitersLists[0]
+		blocked := false
+		var waited time.Duration
+
+		for !q.closed && q.capacity > 0 && itemQueue.Len() >= q.capacity {
+			if !blocked {
+				q.pendingPushes++
+				blocked = true
+			}
+
+			if q.maxPushWaiters > 0 && pushWaitersList.Len() >= q.maxPushWaiters {
+				q.recordDroppedLocked(WaitKindPush)
+				q.pendingPushes--
+				q.mutex.Unlock()
+				q.notifyMaxWaiters()
+				return pushed, ErrMaxWaiters
+			}
+
+			waiterItem := &waiter{
+				waitChan: make(chan interface{}),
+				waiting:  true,
+			}
+			waiterListElement := pushWaitersList.PushBack(waiterItem)
+			q.mutex.Unlock()
+
+			waitStart := time.Now()
+			select {
+			case <-waiterItem.waitChan:
+				waited += time.Since(waitStart)
+				q.mutex.Lock()
+				continue
+			case <-ctx.Done():
+				waited += time.Since(waitStart)
+				waiterItem.mutex.Lock()
+				waiterItem.waiting = false
+				if waiterItem.fired {
+					waiterItem.mutex.Unlock()
+					q.mutex.Lock()
+					q.unblockNextWaiterLocked(pushWaitersList)
+					q.recordWaitLocked(waited, WaitKindPush, true)
+					q.pendingPushes--
+					q.checkDrainDoneLocked()
+					q.mutex.Unlock()
+				} else {
+					waiterItem.mutex.Unlock()
+					q.mutex.Lock()
+					pushWaitersList.Remove(waiterListElement)
+					q.recordWaitLocked(waited, WaitKindPush, true)
+					q.pendingPushes--
+					q.checkDrainDoneLocked()
+					q.mutex.Unlock()
+				}
+				q.notifyWait(waited, WaitKindPush, true)
+				return pushed, ctx.Err()
+			}
+		}
+
+		if blocked {
+			q.pendingPushes--
+		}
+
+		if q.closed {
+			q.checkDrainDoneLocked()
+			q.mutex.Unlock()
+			return pushed, ErrQueueClosed
+		}
+
+		n := len(items) - pushed
+		if q.capacity > 0 {
+			if avail := q.capacity - itemQueue.Len(); n > avail {
+				n = avail
+			}
+		}
+		for i := 0; i < n; i++ {
+			itemQueue.PushBack(items[pushed+i])
+		}
+		pushed += n
+		for i := 0; i < n && q.popWaitersList.Len() > 0; i++ {
+			q.unblockNextWaiterLocked(q.popWaitersList)
+		}
+		q.recordPushLocked(uint64(n))
+		if waited > 0 {
+			q.recordWaitLocked(waited, WaitKindPush, false)
+		}
+		q.mutex.Unlock()
+
+		if waited > 0 {
+			q.notifyWait(waited, WaitKindPush, false)
+		}
+		for i := 0; i < n; i++ {
+			q.notifyPush()
+		}
+	}
+
+	return pushed, nil
+}
+
 // Pop gets an item at the front of the queue. If queue is empty the call
 // will block until either an item is available on the queue or ctx is cancelled.
 // Returns ErrMaxWaiters if maximum number of waiting goroutines is reached
-// as specified by WithMaxPopWaiters() option.
+// as specified by WithMaxPopWaiters() option. When WithPriorities() is
+// used, the priority class an item is popped from is chosen by the
+// configured PriorityPolicy.
 func (q *Bloque) Pop(ctx context.Context) (item interface{}, err error) {
+	if err := q.reserveRateLimit(ctx, q.popLimiter); err != nil {
+		return nil, err
+	}
+
 	q.mutex.Lock()
 
-	for q.itemQueue.Len() == 0 {
+	var waited time.Duration
+	for q.totalLenLocked() == 0 {
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, ErrQueueClosed
+		}
+
 		if q.maxPopWaiters > 0 && q.popWaitersList.Len() >= q.maxPopWaiters {
+			q.recordDroppedLocked(WaitKindPop)
 			q.mutex.Unlock()
+			q.notifyMaxWaiters()
 			return nil, ErrMaxWaiters
 		}
 
@@ -127,14 +434,17 @@ func (q *Bloque) Pop(ctx context.Context) (item interface{}, err error) {
 			waitChan: make(chan interface{}),
 			waiting:  true,
 		}
-		q.popWaitersList.PushBack(waiterItem)
+		waiterListElement := q.popWaitersList.PushBack(waiterItem)
 		q.mutex.Unlock()
 
+		waitStart := time.Now()
 		select {
 		case <-waiterItem.waitChan:
+			waited += time.Since(waitStart)
 			q.mutex.Lock()
 			continue
 		case <-ctx.Done():
+			waited += time.Since(waitStart)
 			waiterItem.mutex.Lock()
 			waiterItem.waiting = false
 			if waiterItem.fired {
@@ -142,28 +452,350 @@ func (q *Bloque) Pop(ctx context.Context) (item interface{}, err error) {
 				waiterItem.mutex.Unlock()
 				q.mutex.Lock()
 				q.unblockNextWaiterLocked(q.popWaitersList)
+				q.recordWaitLocked(waited, WaitKindPop, true)
 				q.mutex.Unlock()
 			} else {
 				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.popWaitersList.Remove(waiterListElement)
+				q.recordWaitLocked(waited, WaitKindPop, true)
+				q.mutex.Unlock()
 			}
+			q.notifyWait(waited, WaitKindPop, true)
 			return nil, ctx.Err()
 		}
 	}
 
-	el := q.itemQueue.Front()
-	val := q.itemQueue.Remove(el)
-	q.unblockNextWaiterLocked(q.pushWaitersList)
+	prio := q.selectPriorityLocked()
+	itemQueue := q.itemQueues[prio]
+	el := itemQueue.Front()
+	val := itemQueue.Remove(el)
+	q.unblockNextWaiterLocked(q.pushWaitersLists[prio])
+	q.recordPopLocked(1)
+	if waited > 0 {
+		q.recordWaitLocked(waited, WaitKindPop, false)
+	}
+	q.checkDrainDoneLocked()
 	q.mutex.Unlock()
 
+	if waited > 0 {
+		q.notifyWait(waited, WaitKindPop, false)
+	}
+	q.notifyPop()
 	return val, nil
 }
 
-// Len returns the current length of the queue.
+// TryPop gets an item at the front of the queue without blocking. It
+// returns ErrEmptyQueue if the queue has no items, or ErrQueueClosed if the
+// queue has been closed and drained. Returns ErrRateLimited if
+// WithPopRateLimit() is set and no token is currently available, since
+// TryPop() cannot block to wait for one.
+func (q *Bloque) TryPop() (interface{}, error) {
+	if err := q.tryReserveRateLimit(q.popLimiter); err != nil {
+		return nil, err
+	}
+
+	q.mutex.Lock()
+
+	if q.totalLenLocked() == 0 {
+		closed := q.closed
+		q.mutex.Unlock()
+		if closed {
+			return nil, ErrQueueClosed
+		}
+		return nil, ErrEmptyQueue
+	}
+
+	prio := q.selectPriorityLocked()
+	itemQueue := q.itemQueues[prio]
+	el := itemQueue.Front()
+	val := itemQueue.Remove(el)
+	q.unblockNextWaiterLocked(q.pushWaitersLists[prio])
+	q.recordPopLocked(1)
+	q.checkDrainDoneLocked()
+	q.mutex.Unlock()
+
+	q.notifyPop()
+	return val, nil
+}
+
+// Peek returns the item at the front of the queue without removing it. It
+// returns ErrEmptyQueue if the queue has no items, or ErrQueueClosed if the
+// queue has been closed and drained. When WithPriorities() is used, this is
+// the item that the configured PriorityPolicy would currently select.
+func (q *Bloque) Peek() (interface{}, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.totalLenLocked() == 0 {
+		if q.closed {
+			return nil, ErrQueueClosed
+		}
+		return nil, ErrEmptyQueue
+	}
+
+	// selectLocked advances weightedRoundRobinPolicy's position, so it is
+	// restored afterwards and Peek() leaves the next Pop()'s choice as is.
+	if p, ok := q.priorityPolicy.(*weightedRoundRobinPolicy); ok {
+		saved := *p
+		defer func() { *p = saved }()
+	}
+	prio := q.selectPriorityLocked()
+	return q.itemQueues[prio].Front().Value, nil
+}
+
+// PopN gets up to max items from the front of the queue. It blocks until
+// either at least minBatch items are available, ctx is cancelled, or the
+// queue is closed, then atomically removes and returns up to max of them.
+// Unlike repeated calls to Pop(), push waiters are woken in a single
+// broadcast pass per batch rather than one at a time. As with Pop(), a
+// single WithPopRateLimit() token is reserved for the whole call, not per
+// item.
+func (q *Bloque) PopN(ctx context.Context, max int, minBatch int) ([]interface{}, error) {
+	if err := q.reserveRateLimit(ctx, q.popLimiter); err != nil {
+		return nil, err
+	}
+
+	q.mutex.Lock()
+
+	var waited time.Duration
+	for q.totalLenLocked() < minBatch {
+		if q.closed {
+			break
+		}
+
+		if q.maxPopWaiters > 0 && q.popWaitersList.Len() >= q.maxPopWaiters {
+			q.recordDroppedLocked(WaitKindPop)
+			q.mutex.Unlock()
+			q.notifyMaxWaiters()
+			return nil, ErrMaxWaiters
+		}
+
+		waiterItem := &waiter{
+			waitChan: make(chan interface{}),
+			waiting:  true,
+		}
+		waiterListElement := q.popWaitersList.PushBack(waiterItem)
+		q.mutex.Unlock()
+
+		waitStart := time.Now()
+		select {
+		case <-waiterItem.waitChan:
+			waited += time.Since(waitStart)
+			q.mutex.Lock()
+			continue
+		case <-ctx.Done():
+			waited += time.Since(waitStart)
+			waiterItem.mutex.Lock()
+			waiterItem.waiting = false
+			if waiterItem.fired {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.unblockNextWaiterLocked(q.popWaitersList)
+				q.recordWaitLocked(waited, WaitKindPop, true)
+				q.mutex.Unlock()
+			} else {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.popWaitersList.Remove(waiterListElement)
+				q.recordWaitLocked(waited, WaitKindPop, true)
+				q.mutex.Unlock()
+			}
+			q.notifyWait(waited, WaitKindPop, true)
+			return nil, ctx.Err()
+		}
+	}
+
+	if q.totalLenLocked() == 0 && q.closed {
+		q.mutex.Unlock()
+		return nil, ErrQueueClosed
+	}
+
+	n := q.totalLenLocked()
+	if n > max {
+		n = max
+	}
+	items := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		prio := q.selectPriorityLocked()
+		itemQueue := q.itemQueues[prio]
+		el := itemQueue.Front()
+		items[i] = itemQueue.Remove(el)
+		q.unblockNextWaiterLocked(q.pushWaitersLists[prio])
+	}
+	q.recordPopLocked(uint64(n))
+	if waited > 0 {
+		q.recordWaitLocked(waited, WaitKindPop, false)
+	}
+	q.checkDrainDoneLocked()
+	q.mutex.Unlock()
+
+	if waited > 0 {
+		q.notifyWait(waited, WaitKindPop, false)
+	}
+	for i := 0; i < n; i++ {
+		q.notifyPop()
+	}
+	return items, nil
+}
+
+// PopAll atomically removes and returns all items currently queued, across
+// all priority classes, without blocking. It is meant as a hard-stop path
+// for use after Close() or Drain(), so that callers can persist or forward
+// any items left in the queue rather than losing them.
+func (q *Bloque) PopAll() []interface{} {
+	q.mutex.Lock()
+
+	items := make([]interface{}, 0, q.totalLenLocked())
+	for prio, itemQueue := range q.itemQueues {
+		for itemQueue.Len() > 0 {
+			el := itemQueue.Front()
+			items = append(items, itemQueue.Remove(el))
+			q.unblockNextWaiterLocked(q.pushWaitersLists[prio])
+		}
+	}
+	q.recordPopLocked(uint64(len(items)))
+	q.checkDrainDoneLocked()
+	q.mutex.Unlock()
+
+	for range items {
+		q.notifyPop()
+	}
+
+	return items
+}
+
+// Len returns the current length of the queue, across all priority classes.
 func (q *Bloque) Len() int {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	return q.itemQueue.Len()
+	return q.totalLenLocked()
+}
+
+// PushWaiters returns the number of currently blocked Push routines, across
+// all priority classes.
+func (q *Bloque) PushWaiters() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	n := 0
+	for _, waiters := range q.pushWaitersLists {
+		n += waiters.Len()
+	}
+	return n
+}
+
+// PopWaiters returns the number of currently blocked Pop routines.
+func (q *Bloque) PopWaiters() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.popWaitersList.Len()
+}
+
+// Drain marks the queue as draining: subsequent Push/PushWithPriority/
+// TryPush/PushN calls immediately return ErrQueueClosed, but items already
+// queued and goroutines already blocked in Push() are still allowed to
+// flow to Pop consumers. It blocks until the queue is empty and all push
+// waiters have been unblocked, or until ctx is cancelled, in which case
+// ctx.Err() is returned and the queue is left draining.
+func (q *Bloque) Drain(ctx context.Context) error {
+	q.mutex.Lock()
+	q.draining = true
+	if q.drainDoneLocked() {
+		q.mutex.Unlock()
+		return nil
+	}
+
+	waiterItem := &waiter{
+		waitChan: make(chan interface{}),
+		waiting:  true,
+	}
+	q.drainWaitersList.PushBack(waiterItem)
+	q.mutex.Unlock()
+
+	select {
+	case <-waiterItem.waitChan:
+		return nil
+	case <-ctx.Done():
+		waiterItem.mutex.Lock()
+		waiterItem.waiting = false
+		waiterItem.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// drainDoneLocked reports whether draining has finished, i.e. the queue is
+// empty and no Push()/PushWithPriority()/PushN() call is still working
+// towards landing its item. Caller must hold q.mutex.
+func (q *Bloque) drainDoneLocked() bool {
+	return q.totalLenLocked() == 0 && q.pendingPushes == 0
+}
+
+// checkDrainDoneLocked wakes up any goroutines blocked in Drain() once
+// draining has finished. Caller must hold q.mutex.
+func (q *Bloque) checkDrainDoneLocked() {
+	if q.draining && q.drainDoneLocked() {
+		q.unblockAllWaitersLocked(q.drainWaitersList)
+	}
+}
+
+// Close marks the queue as closed and unblocks all blocked Pop and Push
+// routines with ErrQueueClosed. If WithGracefulShutdown() was used, Close()
+// first drains the queue as Drain(context.Background()) would, so that
+// items already queued and in-flight pushes are delivered to Pop consumers
+// before the queue is hard-stopped.
+func (q *Bloque) Close() {
+	if q.gracefulShutdown {
+		q.Drain(context.Background())
+	}
+
+	q.mutex.Lock()
+	alreadyClosed := q.closed
+	q.closed = true
+	for q.popWaitersList.Len() > 0 {
+		q.unblockNextWaiterLocked(q.popWaitersList)
+	}
+	for _, waiters := range q.pushWaitersLists {
+		for waiters.Len() > 0 {
+			q.unblockNextWaiterLocked(waiters)
+		}
+	}
+	q.checkDrainDoneLocked()
+	q.mutex.Unlock()
+
+	if alreadyClosed {
+		return
+	}
+
+	if q.adaptive != nil {
+		close(q.adaptive.stopChan)
+	}
+	q.notifyClose()
+}
+
+// totalLenLocked returns the combined length of all priority classes.
+// Caller must hold q.mutex.
+func (q *Bloque) totalLenLocked() int {
+	if len(q.itemQueues) == 1 {
+		return q.itemQueues[0].Len()
+	}
+	n := 0
+	for _, itemQueue := range q.itemQueues {
+		n += itemQueue.Len()
+	}
+	return n
+}
+
+// selectPriorityLocked returns the index of the priority class to pop the
+// next item from. Caller must hold q.mutex and must have already
+// established that totalLenLocked() > 0.
+func (q *Bloque) selectPriorityLocked() int {
+	if len(q.itemQueues) == 1 {
+		return 0
+	}
+	return q.priorityPolicy.selectLocked(q.itemQueues)
 }
 
 func (q *Bloque) unblockNextWaiterLocked(waiters *list.List) {
@@ -179,3 +811,20 @@ func (q *Bloque) unblockNextWaiterLocked(waiters *list.List) {
 		}
 	}
 }
+
+// unblockAllWaitersLocked wakes up every still-interested waiter in waiters,
+// unlike unblockNextWaiterLocked which only wakes the first one. It is used
+// for conditions that are not tied to a single queued item, such as Drain()
+// completing.
+func (q *Bloque) unblockAllWaitersLocked(waiters *list.List) {
+	for waiters.Len() > 0 {
+		el := waiters.Front()
+		w := waiters.Remove(el).(*waiter)
+		w.mutex.Lock()
+		if w.waiting {
+			close(w.waitChan)
+			w.fired = true
+		}
+		w.mutex.Unlock()
+	}
+}
@@ -124,6 +124,75 @@ func TestMaxPopWaiters(t *testing.T) {
 	require.Equal(t, ErrMaxWaiters, err)
 }
 
+func TestTryPushTryPopPeek(t *testing.T) {
+	q := New(WithCapacity(1))
+
+	_, err := q.TryPop()
+	require.Equal(t, ErrEmptyQueue, err)
+
+	err = q.TryPush(1)
+	require.NoError(t, err)
+
+	err = q.TryPush(2)
+	require.Equal(t, ErrQueueFull, err)
+
+	item, err := q.Peek()
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+	require.Equal(t, 1, q.Len())
+
+	item, err = q.TryPop()
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+
+	_, err = q.Peek()
+	require.Equal(t, ErrEmptyQueue, err)
+}
+
+func TestPushNPopN(t *testing.T) {
+	q := New(WithCapacity(5))
+
+	n, err := q.PushN(context.Background(), []interface{}{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, 3, q.Len())
+
+	items, err := q.PopN(context.Background(), 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2}, items)
+	require.Equal(t, 1, q.Len())
+}
+
+func TestPopNBlocksForMinBatch(t *testing.T) {
+	q := New()
+
+	doneChan := make(chan []interface{})
+	go func() {
+		items, err := q.PopN(context.Background(), 4, 3)
+		require.NoError(t, err)
+		doneChan <- items
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+
+	select {
+	case <-doneChan:
+		require.Fail(t, "PopN should still be blocked below minBatch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, q.Push(context.Background(), 3))
+
+	select {
+	case items := <-doneChan:
+		require.Equal(t, []interface{}{1, 2, 3}, items)
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "timeout waiting for PopN")
+	}
+}
+
 func TestOpAfterClose(t *testing.T) {
 	q := New()
 
@@ -210,3 +279,218 @@ func TestClosePushWaiters(t *testing.T) {
 		}
 	}
 }
+
+func TestDrainRejectsNewPushesButLetsItemsFlow(t *testing.T) {
+	q := New()
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+	require.NoError(t, q.Push(ctx, 2))
+
+	drainDone := make(chan error)
+	go func() {
+		drainDone <- q.Drain(ctx)
+	}()
+
+	// Drain() must not return while items are still queued.
+	select {
+	case err := <-drainDone:
+		require.Fail(t, "Drain() returned early", "err: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	err := q.Push(ctx, 3)
+	require.Equal(t, ErrQueueClosed, err)
+
+	item, err := q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+	item, err = q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, item)
+
+	select {
+	case err := <-drainDone:
+		require.NoError(t, err)
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "timeout waiting for Drain() to complete")
+	}
+}
+
+func TestDrainLetsBlockedPushFlowThenUnblocks(t *testing.T) {
+	q := New(WithCapacity(1))
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+
+	pushDone := make(chan error)
+	go func() {
+		pushDone <- q.Push(ctx, 2)
+	}()
+
+	for q.PushWaiters() < 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	drainDone := make(chan error)
+	go func() {
+		drainDone <- q.Drain(ctx)
+	}()
+
+	item, err := q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+
+	select {
+	case err := <-pushDone:
+		require.NoError(t, err)
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "timeout waiting for blocked Push() to complete")
+	}
+
+	item, err = q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, item)
+
+	select {
+	case err := <-drainDone:
+		require.NoError(t, err)
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "timeout waiting for Drain() to complete")
+	}
+}
+
+func TestDrainContextCancelled(t *testing.T) {
+	q := New()
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := q.Drain(drainCtx)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestDrainAfterStalePushWaiterCancellation(t *testing.T) {
+	q := New(WithCapacity(1))
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+
+	// a push that will block then get cancelled; regression test for a
+	// stale (non-removed) entry being left behind in pushWaitersLists.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	pushErr := make(chan error, 1)
+	go func() {
+		pushErr <- q.Push(cancelCtx, 2)
+	}()
+	for q.PushWaiters() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	require.Error(t, <-pushErr)
+	// give the goroutine time to fully return
+	time.Sleep(20 * time.Millisecond)
+
+	// drain the one remaining item so the queue is actually, truly empty
+	// and nobody is waiting to push.
+	_, err := q.Pop(ctx)
+	require.NoError(t, err)
+
+	drainCtx, dcancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer dcancel()
+	// Drain() should return immediately since the queue is truly empty;
+	// it previously hung because the stale waiter kept PushWaiters() > 0.
+	require.NoError(t, q.Drain(drainCtx))
+}
+
+func TestPopAll(t *testing.T) {
+	q := New()
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+	require.NoError(t, q.Push(ctx, 2))
+	require.NoError(t, q.Push(ctx, 3))
+
+	items := q.PopAll()
+	require.Equal(t, []interface{}{1, 2, 3}, items)
+	require.Equal(t, 0, q.Len())
+}
+
+func TestWithGracefulShutdown(t *testing.T) {
+	q := New(WithGracefulShutdown())
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+	require.NoError(t, q.Push(ctx, 2))
+
+	closeDone := make(chan struct{})
+	go func() {
+		q.Close()
+		close(closeDone)
+	}()
+
+	select {
+	case <-closeDone:
+		require.Fail(t, "Close() returned before the queue was drained")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	item, err := q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+	item, err = q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, item)
+
+	select {
+	case <-closeDone:
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "timeout waiting for Close() to complete")
+	}
+
+	_, err = q.Pop(ctx)
+	require.Equal(t, ErrQueueClosed, err)
+}
+
+func TestWithGracefulShutdownDoesNotDropBlockedPush(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		q := New(WithCapacity(1), WithGracefulShutdown())
+		ctx := context.Background()
+
+		require.NoError(t, q.Push(ctx, 1))
+
+		pushDone := make(chan error)
+		go func() {
+			pushDone <- q.Push(ctx, 2)
+		}()
+
+		for q.PushWaiters() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		closeDone := make(chan struct{})
+		go func() {
+			q.Close()
+			close(closeDone)
+		}()
+
+		item, err := q.Pop(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, item)
+
+		select {
+		case err := <-pushDone:
+			require.NoError(t, err, "blocked Push() should be delivered, not dropped, by a graceful Close()")
+		case <-time.After(1 * time.Second):
+			require.Fail(t, "timeout waiting for blocked Push() to complete")
+		}
+
+		item, err = q.Pop(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, item)
+
+		<-closeDone
+	}
+}
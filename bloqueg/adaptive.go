@@ -0,0 +1,174 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloqueg
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultAdaptiveInterval      = time.Second
+	defaultAdaptiveBackoffFactor = 0.5
+)
+
+// BackoffSignal is called by the adaptive capacity calculator on every tick
+// to decide whether the queue's current limit should be backed off. It
+// should return true when the downstream consumer is overloaded.
+type BackoffSignal func() bool
+
+// CapacityChangeFunc is called whenever the adaptive capacity calculator
+// changes the queue's current limit, so that callers can log or emit
+// metrics for the transition.
+type CapacityChangeFunc func(old, new int)
+
+// adaptiveState holds the AIMD adaptive capacity configuration and runtime
+// state for a Bloque[T]. currentLimit is the adaptively adjusted capacity
+// and is stored directly in Bloque[T].capacity, protected by
+// Bloque[T].mutex, so that Push()/Pop() need no special casing for adaptive
+// mode.
+type adaptiveState struct {
+	min           int
+	max           int
+	interval      time.Duration
+	backoffFactor float64
+	backoffSignal BackoffSignal
+	onChange      CapacityChangeFunc
+
+	stopChan chan struct{}
+}
+
+// AdaptiveOption configures adaptive capacity behavior specified with
+// WithAdaptiveCapacity().
+type AdaptiveOption func(*adaptiveState)
+
+// WithBackoffSignal sets the function polled on every adaptive calculator
+// tick to decide whether to back off the current limit. Default is to never
+// back off, i.e. grow up to max.
+func WithBackoffSignal(signal BackoffSignal) AdaptiveOption {
+	return func(a *adaptiveState) {
+		a.backoffSignal = signal
+	}
+}
+
+// WithAdaptiveInterval sets the tick interval of the adaptive capacity
+// calculator. Default is one second.
+func WithAdaptiveInterval(interval time.Duration) AdaptiveOption {
+	return func(a *adaptiveState) {
+		a.interval = interval
+	}
+}
+
+// WithBackoffFactor sets the multiplicative decrease factor applied to the
+// current limit when BackoffSignal() returns true. Default is 0.5.
+func WithBackoffFactor(factor float64) AdaptiveOption {
+	return func(a *adaptiveState) {
+		a.backoffFactor = factor
+	}
+}
+
+// WithCapacityChangeCallback sets a function called whenever the adaptive
+// calculator changes the queue's current limit.
+func WithCapacityChangeCallback(callback CapacityChangeFunc) AdaptiveOption {
+	return func(a *adaptiveState) {
+		a.onChange = callback
+	}
+}
+
+// WithAdaptiveCapacity turns on AIMD adaptive capacity: the queue's
+// effective capacity starts at min and is adjusted by a background
+// calculator goroutine ticking at the configured interval. On each tick, if
+// BackoffSignal() returns true the limit is multiplicatively decreased down
+// to min; otherwise it is additively increased by one up to max. This
+// allows Bloque[T] to self-tune its buffer depth for pipelines whose ideal
+// capacity is unknown at startup. A WithCapacity() given before this option
+// is overridden by min; one given after it replaces the starting capacity,
+// which the first tick then brings within [min, max].
+func WithAdaptiveCapacity[T any](min, max int, opts ...AdaptiveOption) Option[T] {
+	return func(c *config) {
+		a := &adaptiveState{
+			min:           min,
+			max:           max,
+			interval:      defaultAdaptiveInterval,
+			backoffFactor: defaultAdaptiveBackoffFactor,
+			stopChan:      make(chan struct{}),
+		}
+		for _, opt := range opts {
+			opt(a)
+		}
+
+		c.capacity = min
+		c.adaptive = a
+	}
+}
+
+// Capacity returns the queue's current effective capacity. Under
+// WithAdaptiveCapacity() this changes over time; otherwise it is the value
+// passed to WithCapacity().
+func (q *Bloque[T]) Capacity() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.capacity
+}
+
+func (q *Bloque[T]) runAdaptiveCalculator() {
+	ticker := time.NewTicker(q.adaptive.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.adaptiveTick()
+		case <-q.adaptive.stopChan:
+			return
+		}
+	}
+}
+
+func (q *Bloque[T]) adaptiveTick() {
+	a := q.adaptive
+
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return
+	}
+	old := q.capacity
+	q.mutex.Unlock()
+
+	// backoffSignal() is invoked outside the queue's internal lock, just
+	// like onChange below, so it may safely call back into the queue
+	// (e.g. checking Len()) without deadlocking.
+	var backoff bool
+	if a.backoffSignal != nil {
+		backoff = a.backoffSignal()
+	}
+
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return
+	}
+
+	newLimit := old + 1
+	if backoff {
+		newLimit = int(math.Floor(float64(old) * a.backoffFactor))
+	}
+	if newLimit < a.min {
+		newLimit = a.min
+	}
+	if newLimit > a.max {
+		newLimit = a.max
+	}
+	q.capacity = newLimit
+
+	for i := 0; i < newLimit-old; i++ {
+		q.unblockNextWaiterLocked(q.pushWaitersList)
+	}
+	q.mutex.Unlock()
+
+	if newLimit != old && a.onChange != nil {
+		a.onChange(old, newLimit)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloqueg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveCapacityGrows(t *testing.T) {
+	q := New[int](WithAdaptiveCapacity[int](1, 3, WithAdaptiveInterval(10*time.Millisecond)))
+	defer q.Close()
+
+	require.Equal(t, 1, q.Capacity())
+	require.Eventually(t, func() bool {
+		return q.Capacity() == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAdaptiveCapacityBackoffSignalCanCallBackIntoQueue(t *testing.T) {
+	var q *Bloque[int]
+	q = New[int](WithAdaptiveCapacity[int](1, 10,
+		WithAdaptiveInterval(10*time.Millisecond),
+		// regression test: backoffSignal() must not be called while
+		// q.mutex is held, otherwise a signal that calls back into the
+		// queue (a very natural thing to do) deadlocks the calculator
+		// goroutine forever.
+		WithBackoffSignal(func() bool {
+			q.Len()
+			return false
+		}),
+	))
+	defer q.Close()
+
+	require.Eventually(t, func() bool {
+		return q.Capacity() >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, q.Push(ctx, 1))
+}
+
+func TestAdaptiveCapacityUnblocksPushersOnGrowth(t *testing.T) {
+	q := New[int](WithAdaptiveCapacity[int](1, 2, WithAdaptiveInterval(10*time.Millisecond)))
+	defer q.Close()
+
+	require.NoError(t, q.Push(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := q.Push(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, q.Len())
+}
+
+func TestAdaptiveCapacityCloseIsIdempotent(t *testing.T) {
+	q := New[int](WithAdaptiveCapacity[int](1, 2, WithAdaptiveInterval(10*time.Millisecond)))
+
+	// Close() used to unconditionally close(q.adaptive.stopChan), so a
+	// second call panicked with "close of closed channel".
+	require.NotPanics(t, func() {
+		q.Close()
+		q.Close()
+	})
+}
@@ -0,0 +1,325 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+
+// Package bloqueg provides a generic, type-safe counterpart to
+// github.com/technicianted/bloque. It offers the same blocking fifo queue
+// semantics but stores items of type T directly in a slice instead of
+// boxing them in container/list elements, eliminating the per-item
+// allocation and the val.(T) type assertions required by the
+// interface{}-based Bloque.
+package bloqueg
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrMaxWaiters is returned when maximum number of blocked goroutines
+	// on Push() or Pop() calls is reached.
+	ErrMaxWaiters = fmt.Errorf("max waiters reached")
+
+	// ErrQueueClosed is returned when a Push or Pop operation is attempted
+	// after or has been unblocked due to queue being closed.
+	ErrQueueClosed = fmt.Errorf("queue is closed")
+
+	// ErrQueueFull is returned by TryPush() when the queue is at capacity.
+	ErrQueueFull = fmt.Errorf("queue is full")
+
+	// ErrEmptyQueue is returned by TryPop() and Peek() when the queue has
+	// no items.
+	ErrEmptyQueue = fmt.Errorf("queue is empty")
+)
+
+// Bloque is a simple, generic implementation of a blocking fifo queue of T.
+// It allows various constrains to be specified such as maximum capacity,
+// maximum waiters and so on. Its Push(), TryPush(), Pop(), TryPop(), Peek()
+// and Close() behave like those of github.com/technicianted/bloque.Bloque.
+// It is a separate implementation: bloque.Bloque does not use it, and it
+// has none of bloque.Bloque's priority, rate limit, observer, batch or
+// drain options.
+type Bloque[T any] struct {
+	items          []T
+	capacity       int
+	maxPushWaiters int
+	maxPopWaiters  int
+	closed         bool
+	mutex          sync.Mutex
+
+	pushWaitersList *list.List
+	popWaitersList  *list.List
+
+	// adaptive holds the AIMD adaptive capacity state. It is nil unless
+	// WithAdaptiveCapacity() was used.
+	adaptive *adaptiveState
+}
+
+// waiter is used to represent a waiting call.
+type waiter struct {
+	waitChan chan interface{}
+	waiting  bool
+	fired    bool
+	mutex    sync.Mutex
+}
+
+// New creates a new Bloque[T] with opts.
+func New[T any](opts ...Option[T]) *Bloque[T] {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	q := &Bloque[T]{
+		capacity:        c.capacity,
+		maxPushWaiters:  c.maxPushWaiters,
+		maxPopWaiters:   c.maxPopWaiters,
+		pushWaitersList: list.New(),
+		popWaitersList:  list.New(),
+		adaptive:        c.adaptive,
+	}
+
+	if q.adaptive != nil {
+		go q.runAdaptiveCalculator()
+	}
+
+	return q
+}
+
+// Push puts item at the back of the queue. If queue capacity is reached
+// (as specified by WithCapacity() option), the call will block until
+// either an item is removed from the queue or ctx is cancelled.
+// Returns ErrMaxWaiters if maximum number of waiting goroutines is reached
+// as specified by WithMaxPushWaiters() option.
+func (q *Bloque[T]) Push(ctx context.Context, item T) error {
+	q.mutex.Lock()
+
+	for !q.closed && q.capacity > 0 && len(q.items) >= q.capacity {
+		if q.maxPushWaiters > 0 && q.pushWaitersList.Len() >= q.maxPushWaiters {
+			q.mutex.Unlock()
+			return ErrMaxWaiters
+		}
+
+		waiterItem := &waiter{
+			waitChan: make(chan interface{}),
+			waiting:  true,
+		}
+		waiterListElement := q.pushWaitersList.PushBack(waiterItem)
+		q.mutex.Unlock()
+
+		select {
+		case <-waiterItem.waitChan:
+			q.mutex.Lock()
+			continue
+		case <-ctx.Done():
+			waiterItem.mutex.Lock()
+			waiterItem.waiting = false
+			if waiterItem.fired {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.unblockNextWaiterLocked(q.pushWaitersList)
+				q.mutex.Unlock()
+			} else {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.pushWaitersList.Remove(waiterListElement)
+				q.mutex.Unlock()
+			}
+			return ctx.Err()
+		}
+	}
+
+	if q.closed {
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+
+	q.items = append(q.items, item)
+	q.unblockNextWaiterLocked(q.popWaitersList)
+	q.mutex.Unlock()
+	return nil
+}
+
+// TryPush puts item at the back of the queue without blocking. It returns
+// ErrQueueFull if queue capacity is reached, or ErrQueueClosed if the queue
+// has been closed.
+func (q *Bloque[T]) TryPush(item T) error {
+	q.mutex.Lock()
+
+	if q.closed {
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+	if q.capacity > 0 && len(q.items) >= q.capacity {
+		q.mutex.Unlock()
+		return ErrQueueFull
+	}
+
+	q.items = append(q.items, item)
+	q.unblockNextWaiterLocked(q.popWaitersList)
+	q.mutex.Unlock()
+	return nil
+}
+
+// Pop gets an item at the front of the queue. If queue is empty the call
+// will block until either an item is available on the queue or ctx is cancelled.
+// Returns ErrMaxWaiters if maximum number of waiting goroutines is reached
+// as specified by WithMaxPopWaiters() option.
+func (q *Bloque[T]) Pop(ctx context.Context) (item T, err error) {
+	q.mutex.Lock()
+
+	for len(q.items) == 0 {
+		if q.closed {
+			q.mutex.Unlock()
+			return item, ErrQueueClosed
+		}
+
+		if q.maxPopWaiters > 0 && q.popWaitersList.Len() >= q.maxPopWaiters {
+			q.mutex.Unlock()
+			return item, ErrMaxWaiters
+		}
+
+		waiterItem := &waiter{
+			waitChan: make(chan interface{}),
+			waiting:  true,
+		}
+		waiterListElement := q.popWaitersList.PushBack(waiterItem)
+		q.mutex.Unlock()
+
+		select {
+		case <-waiterItem.waitChan:
+			q.mutex.Lock()
+			continue
+		case <-ctx.Done():
+			waiterItem.mutex.Lock()
+			waiterItem.waiting = false
+			if waiterItem.fired {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.unblockNextWaiterLocked(q.popWaitersList)
+				q.mutex.Unlock()
+			} else {
+				waiterItem.mutex.Unlock()
+				q.mutex.Lock()
+				q.popWaitersList.Remove(waiterListElement)
+				q.mutex.Unlock()
+			}
+			return item, ctx.Err()
+		}
+	}
+
+	item = q.popFrontLocked()
+	q.unblockNextWaiterLocked(q.pushWaitersList)
+	q.mutex.Unlock()
+
+	return item, nil
+}
+
+// TryPop gets an item at the front of the queue without blocking. It
+// returns ErrEmptyQueue if the queue has no items, or ErrQueueClosed if the
+// queue has been closed and drained.
+func (q *Bloque[T]) TryPop() (item T, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.items) == 0 {
+		if q.closed {
+			return item, ErrQueueClosed
+		}
+		return item, ErrEmptyQueue
+	}
+
+	item = q.popFrontLocked()
+	q.unblockNextWaiterLocked(q.pushWaitersList)
+	return item, nil
+}
+
+// Peek returns the item at the front of the queue without removing it. It
+// returns ErrEmptyQueue if the queue has no items, or ErrQueueClosed if the
+// queue has been closed and drained.
+func (q *Bloque[T]) Peek() (item T, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.items) == 0 {
+		if q.closed {
+			return item, ErrQueueClosed
+		}
+		return item, ErrEmptyQueue
+	}
+
+	return q.items[0], nil
+}
+
+// Len returns the current length of the queue.
+func (q *Bloque[T]) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.items)
+}
+
+// PushWaiters returns the number of currently blocked Push routines.
+func (q *Bloque[T]) PushWaiters() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.pushWaitersList.Len()
+}
+
+// PopWaiters returns the number of currently blocked Pop routines.
+func (q *Bloque[T]) PopWaiters() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.popWaitersList.Len()
+}
+
+// Close marks the queue as closed and unblocks all blocked Pop and Push
+// routines with ErrQueueClosed.
+func (q *Bloque[T]) Close() {
+	q.mutex.Lock()
+	alreadyClosed := q.closed
+	q.closed = true
+	for q.popWaitersList.Len() > 0 {
+		q.unblockNextWaiterLocked(q.popWaitersList)
+	}
+	for q.pushWaitersList.Len() > 0 {
+		q.unblockNextWaiterLocked(q.pushWaitersList)
+	}
+	q.mutex.Unlock()
+
+	if alreadyClosed {
+		return
+	}
+
+	if q.adaptive != nil {
+		close(q.adaptive.stopChan)
+	}
+}
+
+// popFrontLocked removes and returns the front item. Caller must hold
+// q.mutex. The vacated slot is zeroed so a pointer/interface-typed T does
+// not keep its referent alive past the pop.
+func (q *Bloque[T]) popFrontLocked() T {
+	var zero T
+	item := q.items[0]
+	q.items[0] = zero
+	q.items = q.items[1:]
+	return item
+}
+
+func (q *Bloque[T]) unblockNextWaiterLocked(waiters *list.List) {
+	for waiters.Len() > 0 {
+		el := waiters.Front()
+		w := waiters.Remove(el).(*waiter)
+		w.mutex.Lock()
+		if w.waiting {
+			close(w.waitChan)
+			w.fired = true
+			w.mutex.Unlock()
+			return
+		}
+	}
+}
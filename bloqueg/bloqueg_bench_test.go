@@ -0,0 +1,47 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloqueg
+
+import (
+	"context"
+	"testing"
+)
+
+type benchItem struct {
+	A, B int64
+	C    string
+}
+
+func BenchmarkPushPopInt(b *testing.B) {
+	ctx := context.Background()
+	q := New[int](WithCapacity[int](1024))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = q.Push(ctx, i)
+		_, _ = q.Pop(ctx)
+	}
+}
+
+func BenchmarkPushPopStructPointer(b *testing.B) {
+	ctx := context.Background()
+	q := New[*benchItem](WithCapacity[*benchItem](1024))
+	item := &benchItem{A: 1, B: 2, C: "item"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = q.Push(ctx, item)
+		_, _ = q.Pop(ctx)
+	}
+}
+
+func BenchmarkInterfacePushPopInt(b *testing.B) {
+	ctx := context.Background()
+	q := New[interface{}](WithCapacity[interface{}](1024))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = q.Push(ctx, i)
+		_, _ = q.Pop(ctx)
+	}
+}
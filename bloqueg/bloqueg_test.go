@@ -0,0 +1,142 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloqueg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleOps(t *testing.T) {
+	q := New[int]()
+
+	err := q.Push(context.Background(), 1)
+	require.NoError(t, err)
+	err = q.Push(context.Background(), 2)
+	require.NoError(t, err)
+	err = q.Push(context.Background(), 3)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, q.Len())
+
+	i, err := q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, i)
+	i, err = q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, i)
+	i, err = q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, i)
+}
+
+func TestBlockingPopTimeout(t *testing.T) {
+	q := New[int]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := q.Pop(ctx)
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestBlockingPushTimeout(t *testing.T) {
+	q := New[int](WithCapacity[int](1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := q.Push(ctx, 1)
+	require.NoError(t, err)
+	err = q.Push(ctx, 2)
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestMaxPushWaiters(t *testing.T) {
+	q := New[int](WithCapacity[int](1), WithMaxPushWaiters[int](1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := q.Push(ctx, 1)
+	require.NoError(t, err)
+	startedChan := make(chan interface{})
+	go func() {
+		close(startedChan)
+		q.Push(ctx, 2)
+	}()
+	<-startedChan
+	time.Sleep(10 * time.Millisecond)
+	err = q.Push(ctx, 3)
+	require.Error(t, err)
+	require.Equal(t, ErrMaxWaiters, err)
+}
+
+func TestTryPushTryPopPeek(t *testing.T) {
+	q := New[string](WithCapacity[string](1))
+
+	_, err := q.TryPop()
+	require.Equal(t, ErrEmptyQueue, err)
+
+	err = q.TryPush("a")
+	require.NoError(t, err)
+
+	err = q.TryPush("b")
+	require.Equal(t, ErrQueueFull, err)
+
+	item, err := q.Peek()
+	require.NoError(t, err)
+	require.Equal(t, "a", item)
+
+	item, err = q.TryPop()
+	require.NoError(t, err)
+	require.Equal(t, "a", item)
+}
+
+func TestOpAfterClose(t *testing.T) {
+	q := New[string]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	q.Push(ctx, "1")
+	q.Push(ctx, "2")
+	q.Close()
+	item, err := q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1", item)
+	item, err = q.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "2", item)
+	_, err = q.Pop(ctx)
+	require.Equal(t, ErrQueueClosed, err)
+
+	err = q.Push(ctx, "1")
+	require.Equal(t, ErrQueueClosed, err)
+}
+
+func TestPushWaitersClearedAfterCancellation(t *testing.T) {
+	q := New[int](WithCapacity[int](1))
+	ctx := context.Background()
+
+	require.NoError(t, q.Push(ctx, 1))
+
+	// a push that will block then get cancelled; regression test for a
+	// stale (non-removed) entry being left behind in pushWaitersList.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	pushErr := make(chan error, 1)
+	go func() {
+		pushErr <- q.Push(cancelCtx, 2)
+	}()
+	for q.PushWaiters() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	require.Error(t, <-pushErr)
+	// give the goroutine time to fully return
+	time.Sleep(20 * time.Millisecond)
+
+	require.Equal(t, 0, q.PushWaiters())
+}
@@ -0,0 +1,50 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloqueg
+
+// config holds the constrains shared by all Bloque[T] constructors. It
+// exists as its own type, separate from Bloque[T], so that Option[T]
+// implementations stay simple functions of config regardless of how many
+// constrains are added over time.
+type config struct {
+	capacity       int
+	maxPushWaiters int
+	maxPopWaiters  int
+	// adaptive holds the AIMD adaptive capacity state. It is nil unless
+	// WithAdaptiveCapacity() was used.
+	adaptive *adaptiveState
+}
+
+// Option configures a Bloque[T] created with New().
+type Option[T any] func(*config)
+
+// WithCapacity sets maximum queue capacity to capacity. A value of 0 means
+// unlimited capacity.
+// When the queue reaches capacity, Push() is going to block until items
+// are removed from the queue.
+// You can use WithMaxPushWaiters() to set maximum number of blocked Push()
+// goroutines.
+// Default is unlimited capacity.
+func WithCapacity[T any](capacity int) Option[T] {
+	return func(c *config) {
+		c.capacity = capacity
+	}
+}
+
+// WithMaxPushWaiters sets maximum number of goroutine calls blocked on
+// Push() calls. Once the limit is reached, ErrMaxWaiters is returned.
+// Default is unlimited waiters.
+func WithMaxPushWaiters[T any](maxWaiters int) Option[T] {
+	return func(c *config) {
+		c.maxPushWaiters = maxWaiters
+	}
+}
+
+// WithMaxPopWaiters sets maximum number of goroutine calls blocked on Pop()
+// calls. Once the limit is reached, ErrMaxWaiters is returned.
+// Default is unlimited waiters.
+func WithMaxPopWaiters[T any](maxWaiters int) Option[T] {
+	return func(c *config) {
+		c.maxPopWaiters = maxWaiters
+	}
+}
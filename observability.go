@@ -0,0 +1,192 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import "time"
+
+// waitAvgSmoothing is the weight given to the latest sample in the moving
+// average wait times reported by Stats(). Lower values smooth out spikes
+// over more samples.
+const waitAvgSmoothing = 0.2
+
+// WaitKind identifies which operation an Observer.OnWait() call refers to.
+type WaitKind int
+
+const (
+	// WaitKindPush is reported for time spent blocked in Push()/PushN().
+	WaitKindPush WaitKind = iota
+	// WaitKindPop is reported for time spent blocked in Pop()/PopN().
+	WaitKindPop
+)
+
+// Observer receives callbacks for queue events, so that operators can wire
+// Bloque into Prometheus/OpenTelemetry or any other metrics system without
+// this module embedding a metrics dependency. Callbacks are invoked
+// outside the queue's internal lock, so they may safely call back into the
+// queue.
+type Observer interface {
+	// OnPush is called whenever an item is successfully enqueued.
+	OnPush()
+	// OnPop is called whenever an item is successfully dequeued.
+	OnPop()
+	// OnWait is called whenever a Push()/Pop() call actually blocked,
+	// reporting how long it was blocked for.
+	OnWait(dur time.Duration, kind WaitKind)
+	// OnTimeout is called whenever a blocked call is unblocked by its
+	// context being cancelled rather than by the condition it was
+	// waiting for.
+	OnTimeout()
+	// OnMaxWaiters is called whenever a call is rejected with
+	// ErrMaxWaiters.
+	OnMaxWaiters()
+	// OnClose is called once, when Close() is called.
+	OnClose()
+}
+
+// WithObserver registers observer to receive callbacks for queue events.
+func WithObserver(observer Observer) optionFunc {
+	return func(b *Bloque) {
+		b.observer = observer
+	}
+}
+
+// Stats is a point-in-time snapshot of a Bloque's state and counters,
+// suitable for exposing via a Prometheus collector or similar.
+type Stats struct {
+	// Len is the current number of items in the queue.
+	Len int
+	// Capacity is the queue's current effective capacity, see Capacity().
+	Capacity int
+	// PushWaiters is the current number of goroutines blocked on Push().
+	PushWaiters int
+	// PopWaiters is the current number of goroutines blocked on Pop().
+	PopWaiters int
+	// TotalPushed is the total number of items ever pushed.
+	TotalPushed uint64
+	// TotalPopped is the total number of items ever popped.
+	TotalPopped uint64
+	// TotalPushDropped is the total number of Push()/TryPush()/PushN()
+	// calls rejected outright, i.e. without waiting, by ErrQueueFull or
+	// ErrMaxWaiters.
+	TotalPushDropped uint64
+	// TotalPopDropped is the total number of Pop()/PopN() calls rejected
+	// outright, i.e. without waiting, by ErrMaxWaiters.
+	TotalPopDropped uint64
+	// TotalTimedOut is the total number of blocked Push()/Pop() calls
+	// unblocked by context cancellation rather than by the condition
+	// they were waiting for.
+	TotalTimedOut uint64
+	// AvgPushWait is a moving average of the time Push() calls spent
+	// actually blocked.
+	AvgPushWait time.Duration
+	// AvgPopWait is a moving average of the time Pop() calls spent
+	// actually blocked.
+	AvgPopWait time.Duration
+}
+
+// Stats returns a snapshot of the queue's current state and counters.
+func (q *Bloque) Stats() Stats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	pushWaiters := 0
+	for _, waiters := range q.pushWaitersLists {
+		pushWaiters += waiters.Len()
+	}
+
+	return Stats{
+		Len:              q.totalLenLocked(),
+		Capacity:         q.capacity,
+		PushWaiters:      pushWaiters,
+		PopWaiters:       q.popWaitersList.Len(),
+		TotalPushed:      q.totalPushed,
+		TotalPopped:      q.totalPopped,
+		TotalPushDropped: q.totalPushDropped,
+		TotalPopDropped:  q.totalPopDropped,
+		TotalTimedOut:    q.totalTimedOut,
+		AvgPushWait:      time.Duration(q.avgPushWaitNs),
+		AvgPopWait:       time.Duration(q.avgPopWaitNs),
+	}
+}
+
+// recordPushLocked updates push counters. Caller must hold q.mutex.
+func (q *Bloque) recordPushLocked(n uint64) {
+	q.totalPushed += n
+}
+
+// recordPopLocked updates pop counters. Caller must hold q.mutex.
+func (q *Bloque) recordPopLocked(n uint64) {
+	q.totalPopped += n
+}
+
+// recordDroppedLocked updates the dropped counter for kind. Caller must
+// hold q.mutex.
+func (q *Bloque) recordDroppedLocked(kind WaitKind) {
+	if kind == WaitKindPop {
+		q.totalPopDropped++
+	} else {
+		q.totalPushDropped++
+	}
+}
+
+// recordWaitLocked updates the timed-out counter and the moving average
+// wait time for kind. Caller must hold q.mutex.
+func (q *Bloque) recordWaitLocked(dur time.Duration, kind WaitKind, timedOut bool) {
+	if timedOut {
+		q.totalTimedOut++
+	}
+
+	avg := &q.avgPushWaitNs
+	if kind == WaitKindPop {
+		avg = &q.avgPopWaitNs
+	}
+	if *avg == 0 {
+		*avg = float64(dur)
+	} else {
+		*avg = *avg + waitAvgSmoothing*(float64(dur)-*avg)
+	}
+}
+
+// notifyPush fires the observer's OnPush callback, if any. Must be called
+// outside q.mutex.
+func (q *Bloque) notifyPush() {
+	if q.observer != nil {
+		q.observer.OnPush()
+	}
+}
+
+// notifyPop fires the observer's OnPop callback, if any. Must be called
+// outside q.mutex.
+func (q *Bloque) notifyPop() {
+	if q.observer != nil {
+		q.observer.OnPop()
+	}
+}
+
+// notifyWait fires the observer's OnWait callback, and OnTimeout if
+// timedOut, if any. Must be called outside q.mutex.
+func (q *Bloque) notifyWait(dur time.Duration, kind WaitKind, timedOut bool) {
+	if q.observer == nil {
+		return
+	}
+	q.observer.OnWait(dur, kind)
+	if timedOut {
+		q.observer.OnTimeout()
+	}
+}
+
+// notifyMaxWaiters fires the observer's OnMaxWaiters callback, if any. Must
+// be called outside q.mutex.
+func (q *Bloque) notifyMaxWaiters() {
+	if q.observer != nil {
+		q.observer.OnMaxWaiters()
+	}
+}
+
+// notifyClose fires the observer's OnClose callback, if any. Must be
+// called outside q.mutex.
+func (q *Bloque) notifyClose() {
+	if q.observer != nil {
+		q.observer.OnClose()
+	}
+}
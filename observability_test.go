@@ -0,0 +1,172 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testObserver struct {
+	mutex       sync.Mutex
+	pushes      int
+	pops        int
+	waits       []WaitKind
+	timeouts    int
+	maxWaiters  int
+	closedCalls int
+}
+
+func (o *testObserver) OnPush() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.pushes++
+}
+
+func (o *testObserver) OnPop() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.pops++
+}
+
+func (o *testObserver) OnWait(dur time.Duration, kind WaitKind) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.waits = append(o.waits, kind)
+}
+
+func (o *testObserver) OnTimeout() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.timeouts++
+}
+
+func (o *testObserver) OnMaxWaiters() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.maxWaiters++
+}
+
+func (o *testObserver) OnClose() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.closedCalls++
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	q := New(WithCapacity(5))
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+	_, err := q.Pop(context.Background())
+	require.NoError(t, err)
+
+	stats := q.Stats()
+	require.Equal(t, 1, stats.Len)
+	require.Equal(t, 5, stats.Capacity)
+	require.EqualValues(t, 2, stats.TotalPushed)
+	require.EqualValues(t, 1, stats.TotalPopped)
+}
+
+func TestObserverCallbacks(t *testing.T) {
+	obs := &testObserver{}
+	q := New(WithObserver(obs), WithMaxPopWaiters(1))
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	_, err := q.Pop(context.Background())
+	require.NoError(t, err)
+
+	obs.mutex.Lock()
+	require.Equal(t, 1, obs.pushes)
+	require.Equal(t, 1, obs.pops)
+	obs.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Pop(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	obs.mutex.Lock()
+	require.Equal(t, 1, obs.timeouts)
+	require.Contains(t, obs.waits, WaitKindPop)
+	obs.mutex.Unlock()
+
+	startedChan := make(chan interface{})
+	go func() {
+		close(startedChan)
+		q.Pop(context.Background())
+	}()
+	<-startedChan
+	time.Sleep(10 * time.Millisecond)
+	_, err = q.Pop(context.Background())
+	require.Equal(t, ErrMaxWaiters, err)
+
+	obs.mutex.Lock()
+	require.Equal(t, 1, obs.maxWaiters)
+	obs.mutex.Unlock()
+
+	q.Close()
+	obs.mutex.Lock()
+	require.Equal(t, 1, obs.closedCalls)
+	obs.mutex.Unlock()
+}
+
+func TestStatsTotalDroppedSplitByPushPop(t *testing.T) {
+	q := New(WithCapacity(1), WithMaxPushWaiters(1), WithMaxPopWaiters(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, q.Push(ctx, 1))
+	// this push blocks (queue full), filling the one allowed push waiter
+	startedChan := make(chan interface{})
+	go func() {
+		close(startedChan)
+		q.Push(ctx, 2)
+	}()
+	<-startedChan
+	time.Sleep(10 * time.Millisecond)
+	// dropped on the push side: push waiters already at the limit
+	pushErr := q.Push(ctx, 3)
+	require.Equal(t, ErrMaxWaiters, pushErr)
+
+	q2 := New(WithMaxPopWaiters(1))
+	startedChan2 := make(chan interface{})
+	go func() {
+		close(startedChan2)
+		q2.Pop(ctx)
+	}()
+	<-startedChan2
+	time.Sleep(10 * time.Millisecond)
+	// dropped on the pop side: pop waiters already at the limit
+	_, popErr := q2.Pop(ctx)
+	require.Equal(t, ErrMaxWaiters, popErr)
+
+	stats := q.Stats()
+	require.EqualValues(t, 1, stats.TotalPushDropped)
+	require.EqualValues(t, 0, stats.TotalPopDropped)
+
+	stats2 := q2.Stats()
+	require.EqualValues(t, 0, stats2.TotalPushDropped)
+	require.EqualValues(t, 1, stats2.TotalPopDropped)
+}
+
+func TestObserverPopAll(t *testing.T) {
+	obs := &testObserver{}
+	q := New(WithObserver(obs))
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+	require.NoError(t, q.Push(context.Background(), 3))
+
+	items := q.PopAll()
+	require.Len(t, items, 3)
+
+	obs.mutex.Lock()
+	defer obs.mutex.Unlock()
+	require.Equal(t, 3, obs.pops)
+}
@@ -34,3 +34,15 @@ func WithMaxPopWaiters(maxWaiters int) optionFunc {
 		b.maxPopWaiters = maxWaiters
 	}
 }
+
+// WithGracefulShutdown makes Close() drain the queue first, as calling
+// Drain(context.Background()) would, before unblocking any remaining
+// waiters and marking the queue closed. This lets Close() be used as a
+// single "fully stopped" call in places that don't need the finer-grained
+// "stop accepting, then drain" lifecycle that Drain() offers directly.
+// Default is off, i.e. Close() hard-stops immediately.
+func WithGracefulShutdown() optionFunc {
+	return func(b *Bloque) {
+		b.gracefulShutdown = true
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import "container/list"
+
+// PriorityPolicy decides which priority class Pop() drains from next when
+// WithPriorities() is used.
+type PriorityPolicy interface {
+	// selectLocked returns the index of a non-empty priority class in
+	// itemQueues. It is only called when at least one class is non-empty,
+	// and is called while holding the queue's mutex.
+	selectLocked(itemQueues []*list.List) int
+}
+
+// WithPriorities turns the queue into n independent FIFO priority classes,
+// numbered 0 (highest) to n-1 (lowest). Push() and TryPush() address class
+// 0; use PushWithPriority() to target a specific class. Pop() drains
+// across all classes according to policy. Each class gets its own push
+// waiters list, so capacity backpressure (as set by WithCapacity()) is
+// applied per class, while Pop() waiters remain shared across classes.
+func WithPriorities(n int, policy PriorityPolicy) optionFunc {
+	return func(b *Bloque) {
+		itemQueues := make([]*list.List, n)
+		pushWaitersLists := make([]*list.List, n)
+		for i := 0; i < n; i++ {
+			itemQueues[i] = list.New()
+			pushWaitersLists[i] = list.New()
+		}
+
+		b.itemQueues = itemQueues
+		b.pushWaitersLists = pushWaitersLists
+		b.priorityPolicy = policy
+	}
+}
+
+// strictPriorityPolicy always drains the lowest-numbered non-empty
+// priority class, so higher priority items always go out first.
+type strictPriorityPolicy struct{}
+
+// StrictPriority returns a PriorityPolicy that always pops from the
+// lowest-numbered non-empty priority class, i.e. class 0 is fully drained
+// before class 1 is ever touched, and so on. Under sustained load on a
+// higher priority class, lower priority classes can starve.
+func StrictPriority() PriorityPolicy {
+	return strictPriorityPolicy{}
+}
+
+func (strictPriorityPolicy) selectLocked(itemQueues []*list.List) int {
+	for i, itemQueue := range itemQueues {
+		if itemQueue.Len() > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// weightedRoundRobinPolicy dequeues up to weights[i] items from priority i
+// before moving on to the next non-empty class, bounding how long a lower
+// priority class can be starved by a higher one.
+type weightedRoundRobinPolicy struct {
+	weights   []int
+	current   int
+	remaining int
+}
+
+// WeightedRoundRobin returns a PriorityPolicy that dequeues up to
+// weights[i] items from priority class i before moving on to the next
+// class, cycling back to 0 after the last. len(weights) must equal the n
+// passed to WithPriorities(). This bounds how long a lower priority class
+// can be starved by sustained traffic on a higher one.
+func WeightedRoundRobin(weights []int) PriorityPolicy {
+	return &weightedRoundRobinPolicy{
+		weights: weights,
+		current: -1,
+	}
+}
+
+func (p *weightedRoundRobinPolicy) selectLocked(itemQueues []*list.List) int {
+	n := len(itemQueues)
+	for attempt := 0; attempt < n; attempt++ {
+		if p.remaining <= 0 {
+			p.current = (p.current + 1) % n
+			p.remaining = p.weights[p.current]
+			if p.remaining <= 0 {
+				// weight 0 skips the class here: move straight to the
+				// next class. Only the fallback below dequeues from it.
+				continue
+			}
+		}
+		if itemQueues[p.current].Len() == 0 {
+			p.remaining = 0
+			continue
+		}
+		p.remaining--
+		return p.current
+	}
+	// Every class is either empty or weight-0. selectLocked is only
+	// called when at least one class is non-empty, so fall back to the
+	// lowest-numbered non-empty class rather than starving it forever.
+	for i, itemQueue := range itemQueues {
+		if itemQueue.Len() > 0 {
+			p.current = i
+			p.remaining = 0
+			return i
+		}
+	}
+	return -1
+}
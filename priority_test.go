@@ -0,0 +1,115 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityStrict(t *testing.T) {
+	q := New(WithPriorities(3, StrictPriority()))
+
+	require.NoError(t, q.PushWithPriority(context.Background(), "low", 2))
+	require.NoError(t, q.PushWithPriority(context.Background(), "mid", 1))
+	require.NoError(t, q.PushWithPriority(context.Background(), "high", 0))
+	require.Equal(t, 3, q.Len())
+
+	item, err := q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "high", item)
+	item, err = q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "mid", item)
+	item, err = q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "low", item)
+}
+
+func TestPriorityWeightedRoundRobin(t *testing.T) {
+	q := New(WithPriorities(2, WeightedRoundRobin([]int{2, 1})))
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, q.PushWithPriority(context.Background(), 0, 0))
+	}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, q.PushWithPriority(context.Background(), 1, 1))
+	}
+
+	var order []int
+	for i := 0; i < 6; i++ {
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		order = append(order, item.(int))
+	}
+	require.Equal(t, []int{0, 0, 1, 0, 0, 1}, order)
+}
+
+func TestPriorityWeightedRoundRobinZeroWeightExcludesClass(t *testing.T) {
+	q := New(WithPriorities(2, WeightedRoundRobin([]int{1, 0})))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.PushWithPriority(context.Background(), 0, 0))
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.PushWithPriority(context.Background(), 1, 1))
+	}
+
+	var order []int
+	for i := 0; i < 3; i++ {
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		order = append(order, item.(int))
+	}
+	// class 1 has weight 0, so it is not dequeued from while class 0 still
+	// has items, even though it has items queued.
+	require.Equal(t, []int{0, 0, 0}, order)
+	require.Equal(t, 3, q.Len())
+}
+
+func TestPriorityWeightedRoundRobinAllNonEmptyClassesZeroWeight(t *testing.T) {
+	q := New(WithPriorities(2, WeightedRoundRobin([]int{1, 0})))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.PushWithPriority(context.Background(), i, 1))
+	}
+
+	// class 0 has weight 1 but never gets pushed to, and class 1 is
+	// weight-0 but is the only one holding items: selectLocked must fall
+	// back to it instead of returning -1 and panicking on the itemQueues
+	// index in Pop().
+	for i := 0; i < 3; i++ {
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, i, item)
+	}
+	require.Equal(t, 0, q.Len())
+}
+
+func TestPriorityInvalidPriority(t *testing.T) {
+	q := New(WithPriorities(2, StrictPriority()))
+
+	err := q.PushWithPriority(context.Background(), 1, 5)
+	require.Equal(t, ErrInvalidPriority, err)
+}
+
+func TestPriorityCapacityIsPerClass(t *testing.T) {
+	q := New(WithCapacity(1), WithPriorities(2, StrictPriority()))
+
+	require.NoError(t, q.PushWithPriority(context.Background(), "a", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// priority 1's queue has its own capacity budget, independent of
+	// priority 0 already being full.
+	err := q.PushWithPriority(ctx, "b", 1)
+	require.NoError(t, err)
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	err = q.PushWithPriority(ctx2, "c", 0)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
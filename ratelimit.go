@@ -0,0 +1,84 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned by Push() or Pop() when WithRateLimitFailFast()
+// is set and the operation would otherwise have to wait for a rate limit
+// token to become available.
+var ErrRateLimited = fmt.Errorf("rate limited")
+
+// WithPushRateLimit shapes Push(), PushN() and TryPush() calls with a
+// token bucket allowing r events per second with a maximum burst of burst.
+// Push()/PushN() reserve a token before acquiring the queue's internal
+// lock, so a rate limited caller does not block other goroutines from
+// observing queue state. PushN() reserves a single token for the whole
+// batch, the same as one Push() call. TryPush() always treats the
+// reservation as fail-fast, since it cannot block to wait for a token.
+func WithPushRateLimit(r rate.Limit, burst int) optionFunc {
+	return func(b *Bloque) {
+		b.pushLimiter = rate.NewLimiter(r, burst)
+	}
+}
+
+// WithPopRateLimit shapes Pop(), PopN() and TryPop() calls with a token
+// bucket allowing r events per second with a maximum burst of burst. The
+// same per-call (not per-item) reservation and TryPop() fail-fast caveats
+// documented on WithPushRateLimit() apply here.
+func WithPopRateLimit(r rate.Limit, burst int) optionFunc {
+	return func(b *Bloque) {
+		b.popLimiter = rate.NewLimiter(r, burst)
+	}
+}
+
+// WithRateLimitFailFast causes Push()/Pop() to return ErrRateLimited
+// immediately when no rate limit token is currently available, instead of
+// waiting for one. It has no effect unless WithPushRateLimit() or
+// WithPopRateLimit() is also used.
+func WithRateLimitFailFast() optionFunc {
+	return func(b *Bloque) {
+		b.rateLimitFailFast = true
+	}
+}
+
+// reserveRateLimit blocks, in fail-fast mode returns ErrRateLimited, or in
+// blocking mode waits until limiter allows the call to proceed. limiter may
+// be nil, in which case the call is unthrottled.
+func (q *Bloque) reserveRateLimit(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+
+	if q.rateLimitFailFast {
+		if !limiter.Allow() {
+			return ErrRateLimited
+		}
+		return nil
+	}
+
+	return limiter.WaitN(ctx, 1)
+}
+
+// tryReserveRateLimit reports whether limiter currently has a token
+// available, consuming it if so, without blocking. It is used by
+// TryPush()/TryPop(), which document a non-blocking contract and so
+// cannot honor WithRateLimitFailFast()'s blocking mode: a rate limited
+// call is always treated as fail-fast. limiter may be nil, in which case
+// the call is unthrottled.
+func (q *Bloque) tryReserveRateLimit(limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+
+	if !limiter.AllowN(time.Now(), 1) {
+		return ErrRateLimited
+	}
+	return nil
+}
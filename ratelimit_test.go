@@ -0,0 +1,102 @@
+// Copyright (c) technicianted. All rights reserved.
+// Licensed under the MIT License.
+package bloque
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestPushRateLimitBlocks(t *testing.T) {
+	q := New(WithPushRateLimit(rate.Limit(10), 1))
+
+	start := time.Now()
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestPushRateLimitCancel(t *testing.T) {
+	q := New(WithPushRateLimit(rate.Limit(1), 1))
+
+	require.NoError(t, q.Push(context.Background(), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := q.Push(ctx, 2)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 1, q.Len())
+}
+
+func TestPopRateLimitFailFast(t *testing.T) {
+	q := New(WithPopRateLimit(rate.Limit(1), 1), WithRateLimitFailFast())
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+
+	item, err := q.Pop(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+
+	_, err = q.Pop(context.Background())
+	require.Equal(t, ErrRateLimited, err)
+}
+
+func TestTryPushRateLimited(t *testing.T) {
+	q := New(WithPushRateLimit(rate.Limit(1), 1))
+
+	require.NoError(t, q.TryPush(1))
+	// the single burst token was consumed above, so TryPush() can't block
+	// waiting for the next one and must fail fast.
+	err := q.TryPush(2)
+	require.Equal(t, ErrRateLimited, err)
+	require.Equal(t, 1, q.Len())
+}
+
+func TestTryPopRateLimited(t *testing.T) {
+	q := New(WithPopRateLimit(rate.Limit(1), 1))
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+
+	item, err := q.TryPop()
+	require.NoError(t, err)
+	require.Equal(t, 1, item)
+
+	_, err = q.TryPop()
+	require.Equal(t, ErrRateLimited, err)
+}
+
+func TestPushNRateLimitBlocks(t *testing.T) {
+	q := New(WithPushRateLimit(rate.Limit(10), 1))
+
+	start := time.Now()
+	n, err := q.PushN(context.Background(), []interface{}{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	n, err = q.PushN(context.Background(), []interface{}{4})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestPopNRateLimitFailFast(t *testing.T) {
+	q := New(WithPopRateLimit(rate.Limit(1), 1), WithRateLimitFailFast())
+
+	require.NoError(t, q.Push(context.Background(), 1))
+	require.NoError(t, q.Push(context.Background(), 2))
+
+	items, err := q.PopN(context.Background(), 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1, 2}, items)
+
+	_, err = q.PopN(context.Background(), 1, 1)
+	require.Equal(t, ErrRateLimited, err)
+}